@@ -0,0 +1,139 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// cfsslSignRequest mirrors the subset of CFSSL's /api/v1/cfssl/sign request body this generator needs.
+type cfsslSignRequest struct {
+	CertificateRequest string   `json:"certificate_request"`
+	Hosts              []string `json:"hosts,omitempty"`
+	Profile            string   `json:"profile,omitempty"`
+}
+
+// cfsslSignResponse mirrors the subset of CFSSL's /api/v1/cfssl/sign response body this generator needs.
+type cfsslSignResponse struct {
+	Success bool `json:"success"`
+	Result  struct {
+		Certificate string `json:"certificate"`
+	} `json:"result"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// CFSSLCertGenerator generates a certificate by submitting a CSR to a remote CFSSL signer
+// (https://github.com/cloudflare/cfssl) over its HTTP API.
+type CFSSLCertGenerator struct {
+	// URL is the CFSSL signer's sign endpoint, e.g. "https://cfssl.internal:8888/api/v1/cfssl/sign".
+	URL string
+	// Profile selects the signing profile configured on the CFSSL server, if any.
+	Profile string
+	// CACert is the PEM-encoded CA certificate that signs the certs CFSSL issues; it is returned
+	// verbatim as Artifacts.CACert since CFSSL's sign response does not include it.
+	CACert []byte
+	// HTTPClient is used to call the CFSSL server. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+var _ CertGenerator = &CFSSLCertGenerator{}
+
+func (c *CFSSLCertGenerator) setDefaults() {
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+}
+
+// Generate submits a CSR for dnsName to the configured CFSSL signer and returns the signed certificate.
+func (c *CFSSLCertGenerator) Generate(dnsName string) (*Artifacts, error) {
+	c.setDefaults()
+	if c.URL == "" {
+		return nil, fmt.Errorf("URL must be set on CFSSLCertGenerator")
+	}
+	if len(c.CACert) == 0 {
+		return nil, fmt.Errorf("CACert must be set on CFSSLCertGenerator")
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("generating key: %v", err)
+	}
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: dnsName},
+		DNSNames: []string{dnsName},
+	}, key)
+	if err != nil {
+		return nil, fmt.Errorf("creating CSR: %v", err)
+	}
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER})
+
+	reqBody, err := json.Marshal(cfsslSignRequest{
+		CertificateRequest: string(csrPEM),
+		Hosts:              []string{dnsName},
+		Profile:            c.Profile,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling CFSSL sign request: %v", err)
+	}
+
+	httpResp, err := c.HTTPClient.Post(c.URL, "application/json", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("calling CFSSL signer at %s: %v", c.URL, err)
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading CFSSL signer response: %v", err)
+	}
+
+	var resp cfsslSignResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("decoding CFSSL signer response: %v", err)
+	}
+	if !resp.Success || len(resp.Result.Certificate) == 0 {
+		return nil, fmt.Errorf("CFSSL signer rejected the CSR: %s", cfsslErrorMessages(resp))
+	}
+
+	return &Artifacts{
+		CACert: c.CACert,
+		Cert:   []byte(resp.Result.Certificate),
+		Key:    pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+	}, nil
+}
+
+func cfsslErrorMessages(resp cfsslSignResponse) string {
+	if len(resp.Errors) == 0 {
+		return "no error detail returned"
+	}
+	msg := resp.Errors[0].Message
+	for _, e := range resp.Errors[1:] {
+		msg += "; " + e.Message
+	}
+	return msg
+}
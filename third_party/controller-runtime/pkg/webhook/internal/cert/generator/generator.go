@@ -0,0 +1,35 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator
+
+// Artifacts contains the certificates that are used to prove identity of a webhook server.
+type Artifacts struct {
+	// CACert signs the certificate in Cert. It is used to instruct a client to trust the server
+	// presenting Cert.
+	CACert []byte
+	// Cert is the PEM-encoded certificate for the webhook server, signed by the CA in CACert.
+	Cert []byte
+	// Key is the PEM-encoded private key that corresponds to Cert.
+	Key []byte
+}
+
+// CertGenerator generates a certificate for a given DNS name and returns it along with the CA bundle
+// a client needs in order to trust it.
+type CertGenerator interface {
+	// Generate generates a certificate for dnsName.
+	Generate(dnsName string) (*Artifacts, error)
+}
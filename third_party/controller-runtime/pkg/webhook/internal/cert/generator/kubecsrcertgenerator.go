@@ -0,0 +1,171 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// defaultApprovalTimeout bounds how long KubeCSRCertGenerator waits for its CSR to be approved and signed.
+const defaultApprovalTimeout = 1 * time.Minute
+
+// rootCAConfigMapName and rootCAConfigMapKey identify the ConfigMap kube-controller-manager publishes
+// into every namespace with the cluster's CA bundle.
+const (
+	rootCAConfigMapName = "kube-root-ca.crt"
+	rootCAConfigMapKey  = "ca.crt"
+)
+
+// KubeCSRCertGenerator generates a certificate by submitting a certificates.k8s.io/v1
+// CertificateSigningRequest for dnsName, waiting for it to be approved and signed, and trusting the
+// cluster's own CA bundle (kube-root-ca.crt) rather than shipping a self-signed one.
+type KubeCSRCertGenerator struct {
+	// Client talks to the CertificateSigningRequest and ConfigMap APIs.
+	Client kubernetes.Interface
+	// SignerName is the CSR signer that should sign the request, e.g. "kubernetes.io/kubelet-serving"
+	// or a custom signer managed by a cluster-installed CA. Required.
+	SignerName string
+	// Namespace is where the kube-root-ca.crt ConfigMap is read from. Defaults to "default".
+	Namespace string
+	// ApprovalTimeout bounds how long Generate waits for the CSR to be approved and signed.
+	// Defaults to 1 minute.
+	ApprovalTimeout time.Duration
+}
+
+var _ CertGenerator = &KubeCSRCertGenerator{}
+
+func (k *KubeCSRCertGenerator) setDefaults() {
+	if k.Namespace == "" {
+		k.Namespace = "default"
+	}
+	if k.ApprovalTimeout == 0 {
+		k.ApprovalTimeout = defaultApprovalTimeout
+	}
+}
+
+// Generate submits a CertificateSigningRequest for dnsName, blocks until it is approved and signed, and
+// returns the signed cert together with the cluster's CA bundle.
+func (k *KubeCSRCertGenerator) Generate(dnsName string) (*Artifacts, error) {
+	k.setDefaults()
+	if k.SignerName == "" {
+		return nil, fmt.Errorf("SignerName must be set on KubeCSRCertGenerator")
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("generating key: %v", err)
+	}
+
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: dnsName},
+		DNSNames: []string{dnsName},
+	}, key)
+	if err != nil {
+		return nil, fmt.Errorf("creating CSR: %v", err)
+	}
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER})
+
+	csr := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "webhook-cert-",
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Request:    csrPEM,
+			SignerName: k.SignerName,
+			Usages: []certificatesv1.KeyUsage{
+				certificatesv1.UsageDigitalSignature,
+				certificatesv1.UsageKeyEncipherment,
+				certificatesv1.UsageServerAuth,
+			},
+		},
+	}
+	created, err := k.Client.CertificatesV1().CertificateSigningRequests().Create(context.Background(), csr, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("creating CertificateSigningRequest: %v", err)
+	}
+
+	cert, err := k.waitForCertificate(created.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	caCert, err := k.readClusterCABundle()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Artifacts{
+		CACert: caCert,
+		Cert:   cert,
+		Key:    pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+	}, nil
+}
+
+// waitForCertificate polls the named CertificateSigningRequest until it is signed, denied, or failed.
+func (k *KubeCSRCertGenerator) waitForCertificate(name string) ([]byte, error) {
+	var cert []byte
+	err := wait.PollImmediate(time.Second, k.ApprovalTimeout, func() (bool, error) {
+		csr, err := k.Client.CertificatesV1().CertificateSigningRequests().Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, cond := range csr.Status.Conditions {
+			if cond.Type == certificatesv1.CertificateDenied || cond.Type == certificatesv1.CertificateFailed {
+				return false, fmt.Errorf("CertificateSigningRequest %s was %s: %s", name, cond.Type, cond.Message)
+			}
+		}
+		if len(csr.Status.Certificate) == 0 {
+			return false, nil
+		}
+		cert = csr.Status.Certificate
+		return true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("waiting for CertificateSigningRequest %s to be signed: %v", name, err)
+	}
+	return cert, nil
+}
+
+// readClusterCABundle reads the cluster's CA bundle from the kube-root-ca.crt ConfigMap that
+// kube-controller-manager publishes into every namespace.
+func (k *KubeCSRCertGenerator) readClusterCABundle() ([]byte, error) {
+	cm, err := k.Client.CoreV1().ConfigMaps(k.Namespace).Get(context.Background(), rootCAConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("%s/%s ConfigMap not found: cluster CA bundle is unavailable", k.Namespace, rootCAConfigMapName)
+		}
+		return nil, err
+	}
+	ca, ok := cm.Data[rootCAConfigMapKey]
+	if !ok {
+		return nil, fmt.Errorf("%s/%s ConfigMap has no %q key", k.Namespace, rootCAConfigMapName, rootCAConfigMapKey)
+	}
+	return []byte(ca), nil
+}
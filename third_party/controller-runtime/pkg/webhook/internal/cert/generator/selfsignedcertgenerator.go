@@ -0,0 +1,159 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// validityDuration is how long a self-signed cert and its CA remain valid.
+const validityDuration = 10 * 365 * 24 * time.Hour
+
+// SelfSignedCertGenerator implements CertGenerator by signing the leaf certificate with a CA. The CA is
+// the one set via SetCA or, failing that, one it generates on the first call to Generate and reuses on
+// every later call.
+type SelfSignedCertGenerator struct {
+	caKey  []byte
+	caCert []byte
+}
+
+var _ CertGenerator = &SelfSignedCertGenerator{}
+
+// SetCA sets the PEM-encoded CA key and certificate that should be used to sign the generated
+// certificate, instead of generating a new CA.
+func (cp *SelfSignedCertGenerator) SetCA(caKey, caCert []byte) {
+	cp.caKey = caKey
+	cp.caCert = caCert
+}
+
+// Generate creates a new certificate and key pair for dnsName, signed by a CA. If no CA was
+// configured via SetCA, a new self-signed CA is generated and kept for later calls.
+func (cp *SelfSignedCertGenerator) Generate(dnsName string) (*Artifacts, error) {
+	caKey, caCert, err := cp.loadOrGenerateCA()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("generating key: %v", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("generating serial number: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(validityDuration),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("signing certificate: %v", err)
+	}
+
+	return &Artifacts{
+		CACert: encodeCertPEM(caCert.Raw),
+		Cert:   encodeCertPEM(certDER),
+		Key:    encodeKeyPEM(key),
+	}, nil
+}
+
+func (cp *SelfSignedCertGenerator) loadOrGenerateCA() (*rsa.PrivateKey, *x509.Certificate, error) {
+	if cp.caKey != nil && cp.caCert != nil {
+		key, err := decodeKeyPEM(cp.caKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing CA key: %v", err)
+		}
+		cert, err := decodeCertPEM(cp.caCert)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing CA cert: %v", err)
+		}
+		return key, cert, nil
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating CA key: %v", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating CA serial number: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "webhook-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(validityDuration),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("self-signing CA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing generated CA certificate: %v", err)
+	}
+
+	cp.caKey = encodeKeyPEM(key)
+	cp.caCert = encodeCertPEM(certDER)
+	return key, cert, nil
+}
+
+func encodeCertPEM(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func encodeKeyPEM(key *rsa.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func decodeCertPEM(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func decodeKeyPEM(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
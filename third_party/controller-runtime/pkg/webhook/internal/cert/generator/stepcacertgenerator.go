@@ -0,0 +1,153 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generator
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// stepSignRequest mirrors the subset of step-ca's POST /1.0/sign request body this generator needs. ott
+// is a one-time provisioner token minted out-of-band (e.g. by `step ca token`, or a JWK provisioner
+// service); like smallstep's own step CLI, this generator authenticates with an already-minted token
+// rather than a JOSE library of its own.
+type stepSignRequest struct {
+	CSR string `json:"csr"`
+	OTT string `json:"ott"`
+}
+
+// stepSignResponse mirrors the subset of step-ca's sign response body this generator needs.
+type stepSignResponse struct {
+	Crt     string `json:"crt"`
+	CA      string `json:"ca"`
+	Message string `json:"message"`
+}
+
+// StepCACertGenerator obtains a certificate from a smallstep `step-ca` server's sign endpoint
+// (https://smallstep.com/docs/step-ca) over plain HTTP, authenticating with a one-time provisioner token
+// read from a Secret. It needs no smallstep client SDK: the CSR is built with the standard library, same
+// as CFSSLCertGenerator, and the request/response bodies are the small JSON subset described above.
+type StepCACertGenerator struct {
+	// Client reads ProvisionerTokenSecret.
+	Client client.Client
+	// URL is the step-ca server's sign endpoint, e.g. "https://ca.internal:9000/1.0/sign".
+	URL string
+	// ProvisionerTokenSecret points to a Secret containing a one-time provisioner token under the "ott"
+	// key. The token is minted and rotated out-of-band (e.g. by `step ca token`); this generator only
+	// ever reads it.
+	ProvisionerTokenSecret *types.NamespacedName
+	// HTTPClient is used to call the step-ca server. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+var _ CertGenerator = &StepCACertGenerator{}
+
+func (s *StepCACertGenerator) setDefaults() {
+	if s.HTTPClient == nil {
+		s.HTTPClient = http.DefaultClient
+	}
+}
+
+// Generate submits a CSR for dnsName to the configured step-ca server and returns the signed certificate.
+func (s *StepCACertGenerator) Generate(dnsName string) (*Artifacts, error) {
+	s.setDefaults()
+	if s.Client == nil || s.ProvisionerTokenSecret == nil {
+		return nil, fmt.Errorf("Client and ProvisionerTokenSecret must be set on StepCACertGenerator")
+	}
+	if s.URL == "" {
+		return nil, fmt.Errorf("URL must be set on StepCACertGenerator")
+	}
+
+	ott, err := s.readProvisionerToken()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("generating key: %v", err)
+	}
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: dnsName},
+		DNSNames: []string{dnsName},
+	}, key)
+	if err != nil {
+		return nil, fmt.Errorf("creating CSR: %v", err)
+	}
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER})
+
+	reqBody, err := json.Marshal(stepSignRequest{CSR: string(csrPEM), OTT: string(ott)})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling step-ca sign request: %v", err)
+	}
+
+	httpResp, err := s.HTTPClient.Post(s.URL, "application/json", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("calling step-ca signer at %s: %v", s.URL, err)
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading step-ca signer response: %v", err)
+	}
+
+	var resp stepSignResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return nil, fmt.Errorf("decoding step-ca signer response: %v", err)
+	}
+	if resp.Crt == "" || resp.CA == "" {
+		return nil, fmt.Errorf("step-ca signer rejected the CSR: %s", stepErrorMessage(resp))
+	}
+
+	return &Artifacts{
+		CACert: []byte(resp.CA),
+		Cert:   []byte(resp.Crt),
+		Key:    pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+	}, nil
+}
+
+func (s *StepCACertGenerator) readProvisionerToken() ([]byte, error) {
+	secret := &corev1.Secret{}
+	if err := s.Client.Get(nil, *s.ProvisionerTokenSecret, secret); err != nil {
+		return nil, fmt.Errorf("reading provisioner token secret %s: %v", s.ProvisionerTokenSecret, err)
+	}
+	ott, ok := secret.Data["ott"]
+	if !ok {
+		return nil, fmt.Errorf("secret %s has no %q key", s.ProvisionerTokenSecret, "ott")
+	}
+	return ott, nil
+}
+
+func stepErrorMessage(resp stepSignResponse) string {
+	if resp.Message == "" {
+		return "no error detail returned"
+	}
+	return resp.Message
+}
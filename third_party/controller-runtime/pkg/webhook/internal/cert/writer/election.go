@@ -0,0 +1,157 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package writer
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// LeaderElectionConfig enables leader election for secretCertWriter so that, when multiple replicas of a
+// webhook run simultaneously, only the elected leader provisions and rotates the certificate. The other
+// replicas wait for the Secret to appear and just read it, instead of racing on Create/Update.
+type LeaderElectionConfig struct {
+	// Clientset is used to create the Lease that backs the election.
+	Clientset kubernetes.Interface
+	// LeaseName and LeaseNamespace identify the Lease used to elect a leader.
+	LeaseName      string
+	LeaseNamespace string
+	// Identity uniquely identifies this replica when acquiring the Lease. Defaults to the pod hostname.
+	Identity string
+	// LeaseDuration, RenewDeadline and RetryPeriod are forwarded to leaderelection.LeaderElectionConfig.
+	// They default to 15s/10s/2s, matching client-go's own defaults.
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+	// FollowerTimeout bounds how long a non-leader replica will wait in EnsureCert for the leader to
+	// publish a valid cert, so a wedged or crash-looping leader produces an error instead of a replica
+	// that blocks forever. Defaults to 2 minutes.
+	FollowerTimeout time.Duration
+	// InitialElectionTimeout bounds how long startLeaderTracker waits for the first leadership outcome
+	// (win or lose) to be observed before giving up on waiting and letting EnsureCert proceed on
+	// whatever isLeader currently reads. Without this bound, a Lease that the API server never lets
+	// anyone acquire would wedge the very first EnsureCert call forever. Defaults to
+	// LeaseDuration+RenewDeadline, roughly one full election cycle.
+	InitialElectionTimeout time.Duration
+	// Context bounds the lifetime of the background election goroutine and its Lease renew/release
+	// loop. It should be tied to the writer's own shutdown (e.g. a manager's Context) so the election
+	// stops cleanly instead of outliving every other part of the process. Defaults to
+	// context.Background() if unset, which never stops the goroutine on its own.
+	Context context.Context
+}
+
+func (c *LeaderElectionConfig) setDefaults() {
+	if c.Identity == "" {
+		if host, err := os.Hostname(); err == nil {
+			c.Identity = host
+		}
+	}
+	if c.LeaseDuration == 0 {
+		c.LeaseDuration = 15 * time.Second
+	}
+	if c.RenewDeadline == 0 {
+		c.RenewDeadline = 10 * time.Second
+	}
+	if c.RetryPeriod == 0 {
+		c.RetryPeriod = 2 * time.Second
+	}
+	if c.FollowerTimeout == 0 {
+		c.FollowerTimeout = 2 * time.Minute
+	}
+	if c.InitialElectionTimeout == 0 {
+		c.InitialElectionTimeout = c.LeaseDuration + c.RenewDeadline
+	}
+}
+
+// leaderTracker runs a leaderelection.LeaderElector in the background and lets secretCertWriter.EnsureCert
+// poll the current leadership state without blocking on the election loop itself.
+type leaderTracker struct {
+	isLeader int32
+}
+
+// startLeaderTracker starts the leader election loop for cfg in the background and returns a tracker
+// that reports whether this replica currently holds the lease. It blocks until the first leadership
+// outcome for the Lease is known — either this replica won, another replica won, or
+// InitialElectionTimeout elapses first — so a caller that checks leading() immediately after this
+// returns doesn't race the first tryAcquireOrRenew and wrongly fall back to follower behavior before
+// anyone has had a chance to become leader.
+func startLeaderTracker(ctx context.Context, cfg *LeaderElectionConfig) (*leaderTracker, error) {
+	cfg.setDefaults()
+
+	lock, err := resourcelock.New(
+		resourcelock.LeasesResourceLock,
+		cfg.LeaseNamespace,
+		cfg.LeaseName,
+		cfg.Clientset.CoreV1(),
+		cfg.Clientset.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: cfg.Identity},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &leaderTracker{}
+	decided := make(chan struct{})
+	var decideOnce sync.Once
+	markDecided := func() { decideOnce.Do(func() { close(decided) }) }
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: cfg.LeaseDuration,
+		RenewDeadline: cfg.RenewDeadline,
+		RetryPeriod:   cfg.RetryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				atomic.StoreInt32(&t.isLeader, 1)
+				markDecided()
+			},
+			OnStoppedLeading: func() { atomic.StoreInt32(&t.isLeader, 0) },
+			// OnNewLeader reports another replica winning the Lease. When this replica is the new
+			// leader, the outcome is marked by OnStartedLeading instead, after isLeader is set:
+			// client-go runs each callback on its own goroutine, so OnNewLeader may run first.
+			OnNewLeader: func(identity string) {
+				if identity != cfg.Identity {
+					markDecided()
+				}
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go elector.Run(ctx)
+
+	select {
+	case <-decided:
+	case <-time.After(cfg.InitialElectionTimeout):
+	case <-ctx.Done():
+	}
+	return t, nil
+}
+
+// leading reports whether this replica currently holds the lease.
+func (t *leaderTracker) leading() bool {
+	return t != nil && atomic.LoadInt32(&t.isLeader) == 1
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package writer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/internal/cert/generator"
+)
+
+// externalCertWriter reads certificates that are provisioned by an external PKI (cert-manager, smallstep
+// autocert, a platform operator, ...) out of a k8s secret. It never generates or rotates certs itself.
+type externalCertWriter struct {
+	*ExternalCertWriterOptions
+
+	// dnsName is the DNS name that the certificate is for.
+	dnsName string
+	// dryrun indicates the secret should only be read, never created.
+	dryrun bool
+}
+
+// ExternalCertWriterOptions is options for constructing an externalCertWriter.
+type ExternalCertWriterOptions struct {
+	// Client talks to a kubernetes cluster for reading the secret.
+	Client client.Client
+	// Secret points to the secret that an external PKI populates with the certificates.
+	Secret *types.NamespacedName
+	// PollInterval is how often EnsureCert checks the secret while waiting for it to appear.
+	// Defaults to 2 seconds.
+	PollInterval time.Duration
+	// Timeout bounds how long EnsureCert will wait for the secret to appear and become valid.
+	// Defaults to 1 minute. A zero value from the caller is replaced by the default; to wait
+	// forever, set a very large value.
+	Timeout time.Duration
+	// Cache is used by Watch to obtain an informer on Secret so callers can be notified when the
+	// external PKI rotates the certificate. It is optional; EnsureCert does not require it.
+	Cache cache.Cache
+}
+
+var _ CertWriter = &externalCertWriter{}
+
+func (ops *ExternalCertWriterOptions) setDefaults() {
+	if ops.PollInterval == 0 {
+		ops.PollInterval = 2 * time.Second
+	}
+	if ops.Timeout == 0 {
+		ops.Timeout = 1 * time.Minute
+	}
+}
+
+func (ops *ExternalCertWriterOptions) validate() error {
+	if ops.Client == nil {
+		return errors.New("client must be set in ExternalCertWriterOptions")
+	}
+	if ops.Secret == nil {
+		return errors.New("secret must be set in ExternalCertWriterOptions")
+	}
+	return nil
+}
+
+// NewExternalCertWriter constructs a CertWriter that reads certificates provisioned by an external
+// PKI out of a k8s secret, instead of generating them itself.
+func NewExternalCertWriter(ops ExternalCertWriterOptions) (CertWriter, error) {
+	ops.setDefaults()
+	if err := ops.validate(); err != nil {
+		return nil, err
+	}
+	return &externalCertWriter{
+		ExternalCertWriterOptions: &ops,
+	}, nil
+}
+
+// EnsureCert waits for an external PKI to populate the configured secret with a valid certificate for
+// dnsName, and returns its contents. It never creates, overwrites, or rotates the secret itself: expiry
+// and rotation are treated as the external controller's responsibility.
+func (e *externalCertWriter) EnsureCert(dnsName string, dryrun bool) (*generator.Artifacts, bool, error) {
+	e.dnsName = dnsName
+	e.dryrun = dryrun
+
+	var certs *generator.Artifacts
+	err := wait.PollImmediate(e.PollInterval, e.Timeout, func() (bool, error) {
+		secret := &corev1.Secret{}
+		if err := e.Client.Get(nil, *e.Secret, secret); err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		var err error
+		certs, err = externalSecretToCerts(secret)
+		if err != nil {
+			// The secret exists but isn't usable yet (e.g. cert-manager hasn't finished issuing it).
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("waiting for externally-managed cert in secret %s: %v", e.Secret, err)
+	}
+	// externalCertWriter never writes, so from its perspective nothing ever "changed".
+	return certs, false, nil
+}
+
+// Inject is a no-op: the Secret managed by the external PKI is not owned by this CertWriter, so it
+// should not be tied to the webhook configuration's lifecycle.
+func (e *externalCertWriter) Inject(objs ...runtime.Object) error {
+	return nil
+}
+
+// Watch returns a channel that receives a new Artifacts every time the external PKI rotates the
+// certificate in the backing secret.
+func (e *externalCertWriter) Watch(ctx context.Context) (<-chan *generator.Artifacts, error) {
+	if e.Cache == nil {
+		return nil, errors.New("Cache must be set in ExternalCertWriterOptions to use Watch")
+	}
+	return watchSecret(ctx, e.Cache, *e.Secret)
+}
+
+// externalSecretToCerts extracts and validates Artifacts from a secret populated by an external PKI.
+// It supports both the kubernetes.io/tls Secret layout (tls.crt/tls.key, plus the ca.crt key that
+// cert-manager adds) and this module's own CACertName/ServerCertName/ServerKeyName layout. A CA
+// certificate is required in either layout.
+func externalSecretToCerts(secret *corev1.Secret) (*generator.Artifacts, error) {
+	if secret.Data == nil {
+		return nil, fmt.Errorf("secret %s/%s has no data", secret.Namespace, secret.Name)
+	}
+
+	cert := secret.Data[ServerCertName]
+	key := secret.Data[ServerKeyName]
+	ca := secret.Data[CACertName]
+	if len(cert) == 0 || len(key) == 0 {
+		cert = secret.Data[corev1.TLSCertKey]
+		key = secret.Data[corev1.TLSPrivateKeyKey]
+	}
+	if len(ca) == 0 {
+		ca = secret.Data["ca.crt"]
+	}
+
+	if len(cert) == 0 || len(key) == 0 {
+		return nil, fmt.Errorf("secret %s/%s does not contain a cert/key pair", secret.Namespace, secret.Name)
+	}
+	if len(ca) == 0 {
+		return nil, fmt.Errorf("secret %s/%s does not contain a CA certificate", secret.Namespace, secret.Name)
+	}
+
+	pair, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return nil, fmt.Errorf("secret %s/%s contains an invalid cert/key pair: %v", secret.Namespace, secret.Name, err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("secret %s/%s contains an unparsable certificate: %v", secret.Namespace, secret.Name, err)
+	}
+	if time.Now().After(leaf.NotAfter) {
+		return nil, fmt.Errorf("secret %s/%s contains an expired certificate (NotAfter: %v)", secret.Namespace, secret.Name, leaf.NotAfter)
+	}
+	block, _ := pem.Decode(ca)
+	if block == nil {
+		return nil, fmt.Errorf("secret %s/%s contains a malformed CA certificate", secret.Namespace, secret.Name)
+	}
+	if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+		return nil, fmt.Errorf("secret %s/%s contains a malformed CA certificate: %v", secret.Namespace, secret.Name, err)
+	}
+
+	return &generator.Artifacts{
+		CACert: ca,
+		Cert:   cert,
+		Key:    key,
+	}, nil
+}
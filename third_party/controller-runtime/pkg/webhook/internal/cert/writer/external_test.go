@@ -0,0 +1,116 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package writer
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func selfSignedPair(t *testing.T, notBefore time.Time, lifetime time.Duration) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.Add(lifetime),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating self-signed cert: %v", err)
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return certPEM, keyPEM
+}
+
+func TestExternalSecretToCerts(t *testing.T) {
+	certPEM, keyPEM := selfSignedPair(t, time.Now(), 90*24*time.Hour)
+	expiredPEM, expiredKeyPEM := selfSignedPair(t, time.Now().Add(-2*time.Hour), time.Hour)
+
+	tests := map[string]struct {
+		data    map[string][]byte
+		wantErr bool
+	}{
+		"this module's own layout": {
+			data: map[string][]byte{
+				CACertName:     certPEM,
+				ServerCertName: certPEM,
+				ServerKeyName:  keyPEM,
+			},
+		},
+		"cert-manager's kubernetes.io/tls layout": {
+			data: map[string][]byte{
+				corev1.TLSCertKey:       certPEM,
+				corev1.TLSPrivateKeyKey: keyPEM,
+				"ca.crt":                certPEM,
+			},
+		},
+		"missing cert/key pair": {
+			data:    map[string][]byte{CACertName: certPEM},
+			wantErr: true,
+		},
+		"missing CA cert": {
+			data: map[string][]byte{
+				ServerCertName: certPEM,
+				ServerKeyName:  keyPEM,
+			},
+			wantErr: true,
+		},
+		"expired certificate": {
+			data: map[string][]byte{
+				CACertName:     expiredPEM,
+				ServerCertName: expiredPEM,
+				ServerKeyName:  expiredKeyPEM,
+			},
+			wantErr: true,
+		},
+		"no data at all": {
+			data:    nil,
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			secret := &corev1.Secret{Data: tt.data}
+			certs, err := externalSecretToCerts(secret)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(certs.Cert) == 0 || len(certs.Key) == 0 || len(certs.CACert) == 0 {
+				t.Fatalf("expected fully populated Artifacts, got %+v", certs)
+			}
+		})
+	}
+}
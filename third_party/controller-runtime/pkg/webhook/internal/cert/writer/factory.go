@@ -0,0 +1,53 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package writer
+
+import "fmt"
+
+// CertWriterType selects which CertWriter implementation provisions the webhook's certificate.
+type CertWriterType string
+
+const (
+	// SecretCertWriterType provisions a self-signed (or otherwise generated) cert and persists it in a
+	// k8s secret, rotating it itself. This is the default.
+	SecretCertWriterType CertWriterType = "secret"
+	// ExternalCertWriterType reads a cert that is provisioned and rotated by an external controller,
+	// such as cert-manager, out of a k8s secret.
+	ExternalCertWriterType CertWriterType = "external"
+)
+
+// NewCertWriterOptions configures NewCertWriter.
+type NewCertWriterOptions struct {
+	// CertWriterType selects the CertWriter implementation to construct. Defaults to SecretCertWriterType.
+	CertWriterType CertWriterType
+	// SecretCertWriterOptions is used when CertWriterType is SecretCertWriterType (the default).
+	SecretCertWriterOptions SecretCertWriterOptions
+	// ExternalCertWriterOptions is used when CertWriterType is ExternalCertWriterType.
+	ExternalCertWriterOptions ExternalCertWriterOptions
+}
+
+// NewCertWriter constructs the CertWriter implementation selected by ops.CertWriterType.
+func NewCertWriter(ops NewCertWriterOptions) (CertWriter, error) {
+	switch ops.CertWriterType {
+	case "", SecretCertWriterType:
+		return NewSecretCertWriter(ops.SecretCertWriterOptions)
+	case ExternalCertWriterType:
+		return NewExternalCertWriter(ops.ExternalCertWriterOptions)
+	default:
+		return nil, fmt.Errorf("unknown CertWriterType %q", ops.CertWriterType)
+	}
+}
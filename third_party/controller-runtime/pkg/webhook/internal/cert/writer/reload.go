@@ -0,0 +1,97 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package writer
+
+import (
+	"context"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/internal/cert/generator"
+)
+
+// watchSecret returns a channel that receives a new Artifacts every time the Secret named by key is
+// added or updated, using an informer obtained from informers. The channel is closed once ctx is done,
+// and the event handler registered on the (shared) informer is removed at the same time, so this call
+// leaves nothing running against the informer once its caller stops listening.
+func watchSecret(ctx context.Context, informers cache.Cache, key types.NamespacedName) (<-chan *generator.Artifacts, error) {
+	informer, err := informers.GetInformer(&corev1.Secret{})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan *generator.Artifacts)
+
+	// mu guards closed: it must be held for the duration of a send attempt so the shutdown goroutine
+	// can't close out while emit is still (potentially) sending on it.
+	var mu sync.Mutex
+	closed := false
+
+	emit := func(obj interface{}) {
+		secret, ok := obj.(*corev1.Secret)
+		if !ok || secret.Namespace != key.Namespace || secret.Name != key.Name {
+			return
+		}
+		certs := secretToCerts(secret)
+		if certs == nil {
+			return
+		}
+		// A kubernetes.io/tls Secret (e.g. one written by cert-manager) keeps the pair under
+		// tls.crt/tls.key and the CA under ca.crt.
+		if len(certs.Cert) == 0 && len(certs.Key) == 0 {
+			certs.Cert = secret.Data[corev1.TLSCertKey]
+			certs.Key = secret.Data[corev1.TLSPrivateKeyKey]
+		}
+		if len(certs.CACert) == 0 {
+			certs.CACert = secret.Data["ca.crt"]
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case out <- certs:
+		case <-ctx.Done():
+		}
+	}
+
+	registration, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    emit,
+		UpdateFunc: func(_, newObj interface{}) { emit(newObj) },
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		// Stop new events from reaching emit before we close out, so a handler invocation racing
+		// this shutdown can't select the send case on an already-closed channel.
+		_ = informer.RemoveEventHandler(registration)
+
+		mu.Lock()
+		closed = true
+		close(out)
+		mu.Unlock()
+	}()
+	return out, nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package writer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"sync"
+
+	"sigs.k8s.io/controller-runtime/pkg/webhook/internal/cert/generator"
+)
+
+// DynamicCertReloader serves the most recently observed certificate to a TLS listener, consuming a
+// CertWriter's Watch channel so a long-lived webhook server picks up a rotated certificate without a
+// restart. This is the dynamiccertificates-style reloader Watch exists to feed: plug it into a TLS
+// listener via its GetCertificate method, e.g.
+//
+//	reloader, err := writer.NewDynamicCertReloader(ctx, certWriter, initialArtifacts)
+//	server.TLSConfig = &tls.Config{GetCertificate: reloader.GetCertificate}
+type DynamicCertReloader struct {
+	certMu sync.RWMutex
+	cert   *tls.Certificate
+}
+
+// NewDynamicCertReloader constructs a DynamicCertReloader seeded with initial (the Artifacts EnsureCert
+// already returned, if any), then starts consuming cw.Watch(ctx) in the background to keep it current.
+// It stops consuming once ctx is done, at which point GetCertificate keeps serving the last cert it saw.
+func NewDynamicCertReloader(ctx context.Context, cw CertWriter, initial *generator.Artifacts) (*DynamicCertReloader, error) {
+	r := &DynamicCertReloader{}
+	if initial != nil {
+		if err := r.set(initial); err != nil {
+			return nil, err
+		}
+	}
+
+	updates, err := cw.Watch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	go r.run(updates)
+	return r, nil
+}
+
+// run applies every Artifacts received on updates until the channel is closed (i.e. ctx passed to
+// NewDynamicCertReloader is done). A malformed update is dropped rather than propagated, so one bad
+// rotation can't take a previously-healthy listener down.
+func (r *DynamicCertReloader) run(updates <-chan *generator.Artifacts) {
+	for certs := range updates {
+		_ = r.set(certs)
+	}
+}
+
+func (r *DynamicCertReloader) set(certs *generator.Artifacts) error {
+	pair, err := tls.X509KeyPair(certs.Cert, certs.Key)
+	if err != nil {
+		return err
+	}
+	r.certMu.Lock()
+	r.cert = &pair
+	r.certMu.Unlock()
+	return nil
+}
+
+// GetCertificate implements the signature of crypto/tls.Config.GetCertificate, returning the most
+// recently observed certificate for every incoming handshake.
+func (r *DynamicCertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.certMu.RLock()
+	defer r.certMu.RUnlock()
+	if r.cert == nil {
+		return nil, errors.New("DynamicCertReloader: no certificate has been loaded yet")
+	}
+	return r.cert, nil
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package writer
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// jitterFraction bounds the jitter applied to a renewal deadline, as a fraction of the renewal window,
+// to avoid every replica renewing at the exact same instant.
+const jitterFraction = 0.1
+
+// renewalWindow returns how long before a cert's expiry it should be renewed. renewBefore of 0 means
+// 1/3 of the cert's own lifetime. minimumValidity is a floor: the window is never shorter than it, even
+// after jitter is applied. The result is jittered by up to ±jitterFraction to spread renewals out across
+// replicas. The jitter is derived deterministically from the cert's serial number rather than rolled
+// fresh on every call, so repeated calls for the same cert (e.g. once to record NextRenewal, once to
+// decide whether it's still valid) always agree on the same deadline.
+func renewalWindow(leaf *x509.Certificate, renewBefore, minimumValidity time.Duration) time.Duration {
+	window := renewBefore
+	if window == 0 {
+		window = leaf.NotAfter.Sub(leaf.NotBefore) / 3
+	}
+	if window < minimumValidity {
+		window = minimumValidity
+	}
+	jitter := time.Duration((certJitter(leaf)*2 - 1) * jitterFraction * float64(window))
+	if window += jitter; window < minimumValidity {
+		window = minimumValidity
+	}
+	return window
+}
+
+// certJitter deterministically maps leaf's serial number to a float in [0, 1), so the jitter applied to
+// a given cert's renewal deadline is stable across repeated calls but still varies from cert to cert.
+func certJitter(leaf *x509.Certificate) float64 {
+	h := fnv.New32a()
+	_, _ = h.Write(leaf.SerialNumber.Bytes())
+	return float64(h.Sum32()) / float64(1<<32)
+}
+
+// certRenewalDeadline parses a PEM-encoded certificate and returns the time at which it should be
+// renewed, given the RenewBefore/MinimumValidity settings that apply to it.
+func certRenewalDeadline(certPEM []byte, renewBefore, minimumValidity time.Duration) (time.Time, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return time.Time{}, fmt.Errorf("no PEM data found in certificate")
+	}
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return leaf.NotAfter.Add(-renewalWindow(leaf, renewBefore, minimumValidity)), nil
+}
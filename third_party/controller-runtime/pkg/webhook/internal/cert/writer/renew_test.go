@@ -0,0 +1,120 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package writer
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func leafWithLifetime(serial int64, lifetime time.Duration) *x509.Certificate {
+	notBefore := time.Now()
+	return &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.Add(lifetime),
+	}
+}
+
+func TestRenewalWindow(t *testing.T) {
+	const lifetime = 90 * 24 * time.Hour
+	const minimumValidity = 30 * 24 * time.Hour
+
+	tests := map[string]struct {
+		renewBefore time.Duration
+		minValidity time.Duration
+		// wantBase is the window before jitter; the result must lie within ±jitterFraction of it.
+		wantBase time.Duration
+	}{
+		"defaults to a third of the cert's lifetime": {
+			renewBefore: 0,
+			minValidity: 0,
+			wantBase:    lifetime / 3,
+		},
+		"honors an explicit renewBefore above the floor": {
+			renewBefore: 45 * 24 * time.Hour,
+			minValidity: minimumValidity,
+			wantBase:    45 * 24 * time.Hour,
+		},
+		"never drops below minimumValidity even after jitter": {
+			// renewBefore sits right at minimumValidity, so -10% jitter would otherwise push the
+			// final window under the floor.
+			renewBefore: minimumValidity,
+			minValidity: minimumValidity,
+			wantBase:    minimumValidity,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Try enough distinct serial numbers to exercise both jitter directions.
+			for serial := int64(0); serial < 16; serial++ {
+				leaf := leafWithLifetime(serial, lifetime)
+				window := renewalWindow(leaf, tt.renewBefore, tt.minValidity)
+				if window < tt.minValidity {
+					t.Fatalf("serial %d: renewalWindow = %s, want >= minimumValidity %s", serial, window, tt.minValidity)
+				}
+				maxJitter := time.Duration(jitterFraction * float64(tt.wantBase))
+				if window < tt.wantBase-maxJitter || window > tt.wantBase+maxJitter {
+					t.Fatalf("serial %d: renewalWindow = %s, want within %s of %s", serial, window, maxJitter, tt.wantBase)
+				}
+			}
+		})
+	}
+}
+
+func selfSignedLeafPEM(t *testing.T, notBefore time.Time, lifetime time.Duration) []byte {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.Add(lifetime),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating self-signed cert: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestCertRenewalDeadline(t *testing.T) {
+	lifetime := 90 * 24 * time.Hour
+	notBefore := time.Now().Add(-lifetime / 2)
+	certPEM := selfSignedLeafPEM(t, notBefore, lifetime)
+
+	deadline, err := certRenewalDeadline(certPEM, 0, 0)
+	if err != nil {
+		t.Fatalf("certRenewalDeadline returned an error: %v", err)
+	}
+	notAfter := notBefore.Add(lifetime)
+	if !deadline.Before(notAfter) {
+		t.Fatalf("deadline %s is not before NotAfter %s", deadline, notAfter)
+	}
+
+	if _, err := certRenewalDeadline([]byte("not a pem block"), 0, 0); err == nil {
+		t.Fatal("expected an error for a non-PEM input, got nil")
+	}
+}
@@ -0,0 +1,419 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package writer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	apiregistrationv1 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/internal/cert/generator"
+)
+
+// secretCertWriter provisions the certificate by reading and writing to the k8s secrets.
+type secretCertWriter struct {
+	*SecretCertWriterOptions
+
+	// dnsName is the DNS name that the certificate is for.
+	dnsName string
+	// dryrun indicates sending the create/update request to the server or output to the writer in yaml format.
+	dryrun bool
+	// leaderOnce guards starting the leader tracker exactly once, so concurrent EnsureCert calls can't
+	// each spin up a duplicate LeaderElector racing on the same Lease.
+	leaderOnce sync.Once
+	// leader tracks this replica's leadership state when LeaderElection is configured. Set once, under
+	// leaderOnce, alongside leaderErr.
+	leader    *leaderTracker
+	leaderErr error
+
+	// nextRenewalMu guards nextRenewal.
+	nextRenewalMu sync.RWMutex
+	// nextRenewal is the deadline at which the most recently read cert is due to be renewed.
+	nextRenewal time.Time
+}
+
+// SecretCertWriterOptions is options for constructing a secretCertWriter.
+type SecretCertWriterOptions struct {
+	// client talks to a kubernetes cluster for creating the secret.
+	Client client.Client
+	// certGenerator generates the certificates.
+	CertGenerator generator.CertGenerator
+	// secret points the secret that contains certificates that written by the CertWriter.
+	Secret *types.NamespacedName
+	// Writer is used in dryrun mode for writing the objects in yaml format.
+	Writer io.Writer
+	// Cache is used by Watch to obtain an informer on Secret so callers can be notified when the
+	// certificate is rotated. It is optional; EnsureCert does not require it.
+	Cache cache.Cache
+	// LeaderElection, if set, ensures that only the elected leader among multiple webhook replicas
+	// provisions and rotates the certificate; the rest wait for it to appear and just read it.
+	LeaderElection *LeaderElectionConfig
+	// RenewBefore is how long before the certificate's expiry it should be renewed. Defaults to 1/3 of
+	// the certificate's own lifetime.
+	RenewBefore time.Duration
+	// MinimumValidity is a floor under RenewBefore: however RenewBefore is computed, the certificate is
+	// never allowed to come closer than MinimumValidity to its expiry before being renewed.
+	MinimumValidity time.Duration
+	// BlockOwnerDeletion is set on the ownerReferences Inject adds to the managed Secret.
+	BlockOwnerDeletion bool
+}
+
+// electionContext returns the context that bounds the lifetime of the LeaderElection goroutine, falling
+// back to context.Background() if the caller didn't tie it to anything. A caller that wants the election
+// (and its Lease renew loop) to stop on shutdown must set LeaderElection.Context itself.
+func (ops *SecretCertWriterOptions) electionContext() context.Context {
+	if ops.LeaderElection != nil && ops.LeaderElection.Context != nil {
+		return ops.LeaderElection.Context
+	}
+	return context.Background()
+}
+
+var _ CertWriter = &secretCertWriter{}
+
+func (ops *SecretCertWriterOptions) setDefaults() {
+	if ops.CertGenerator == nil {
+		ops.CertGenerator = &generator.SelfSignedCertGenerator{}
+	}
+	if ops.Writer == nil {
+		ops.Writer = os.Stdout
+	}
+}
+
+func (ops *SecretCertWriterOptions) validate() error {
+	if ops.Client == nil {
+		return errors.New("client must be set in SecretCertWriterOptions")
+	}
+	if ops.Secret == nil {
+		return errors.New("secret must be set in SecretCertWriterOptions")
+	}
+	return nil
+}
+
+// NewSecretCertWriter constructs a CertWriter that persists the certificate in a k8s secret.
+func NewSecretCertWriter(ops SecretCertWriterOptions) (CertWriter, error) {
+	ops.setDefaults()
+	err := ops.validate()
+	if err != nil {
+		return nil, err
+	}
+	return &secretCertWriter{
+		SecretCertWriterOptions: &ops,
+	}, nil
+}
+
+// EnsureCert provisions certificates for a webhookClientConfig by writing the certificates to a k8s secret.
+// When LeaderElection is configured, only the elected leader provisions or refreshes the certs; the other
+// replicas block here until the leader has published a valid Secret, then just read it.
+func (s *secretCertWriter) EnsureCert(dnsName string, dryrun bool) (*generator.Artifacts, bool, error) {
+	// Create or refresh the certs based on clientConfig
+	s.dryrun = dryrun
+	s.dnsName = dnsName
+
+	if s.LeaderElection != nil && !s.dryrun {
+		s.leaderOnce.Do(func() {
+			s.leader, s.leaderErr = startLeaderTracker(s.electionContext(), s.LeaderElection)
+		})
+		if s.leaderErr != nil {
+			return nil, false, s.leaderErr
+		}
+		if !s.leader.leading() {
+			return s.waitForLeaderCert()
+		}
+	}
+	return handleCommon(s.dnsName, s)
+}
+
+// waitForLeaderCert blocks until the elected leader has published a valid cert for s.dnsName in the
+// backing Secret, then returns it. It never creates or overwrites the Secret itself. It gives up after
+// LeaderElection.FollowerTimeout, so a leader that never manages to publish a cert produces an error
+// instead of wedging its followers forever.
+func (s *secretCertWriter) waitForLeaderCert() (*generator.Artifacts, bool, error) {
+	var certs *generator.Artifacts
+	err := wait.PollImmediate(2*time.Second, s.LeaderElection.FollowerTimeout, func() (bool, error) {
+		found, err := s.read()
+		if _, ok := err.(notFoundError); ok {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if !s.valid(found, s.dnsName) {
+			return false, nil
+		}
+		certs = found
+		return true, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		err = fmt.Errorf("timed out after %s waiting for the leader to publish a valid cert for %s", s.LeaderElection.FollowerTimeout, s.dnsName)
+	}
+	return certs, false, err
+}
+
+// NextRenewal returns the time at which the most recently read certificate is due to be renewed, so a
+// caller can schedule a timer instead of polling EnsureCert. It returns the zero Time if no certificate
+// has been read yet.
+func (s *secretCertWriter) NextRenewal() time.Time {
+	s.nextRenewalMu.RLock()
+	defer s.nextRenewalMu.RUnlock()
+	return s.nextRenewal
+}
+
+// Watch returns a channel that receives a new Artifacts every time the backing secret's data changes,
+// so callers can reload the certificate without restarting the process.
+func (s *secretCertWriter) Watch(ctx context.Context) (<-chan *generator.Artifacts, error) {
+	if s.Cache == nil {
+		return nil, errors.New("Cache must be set in SecretCertWriterOptions to use Watch")
+	}
+	return watchSecret(ctx, s.Cache, *s.Secret)
+}
+
+var _ certReadWriter = &secretCertWriter{}
+
+func (s *secretCertWriter) buildSecret() (*corev1.Secret, *generator.Artifacts, error) {
+	certs, err := s.CertGenerator.Generate(s.dnsName)
+	if err != nil {
+		return nil, nil, err
+	}
+	secret := certsToSecret(certs, *s.Secret)
+	return secret, certs, err
+}
+
+func (s *secretCertWriter) write() (*generator.Artifacts, error) {
+	secret, certs, err := s.buildSecret()
+	if err != nil {
+		return nil, err
+	}
+	if s.dryrun {
+		return certs, s.dryrunWrite(secret)
+	}
+	err = s.Client.Create(nil, secret)
+	if apierrors.IsAlreadyExists(err) {
+		return nil, alreadyExistError{err}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating secret %s: %v", *s.Secret, err)
+	}
+	return certs, nil
+}
+
+// overwrite replaces the existing certs with freshly generated ones. It uses the Secret's resourceVersion
+// as a compare-and-swap token, retrying on conflict, so that concurrent writers never clobber each
+// other's CA with a blind Update.
+func (s *secretCertWriter) overwrite() (*generator.Artifacts, error) {
+	secret, certs, err := s.buildSecret()
+	if err != nil {
+		return nil, err
+	}
+	if s.dryrun {
+		return certs, s.dryrunWrite(secret)
+	}
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current := &corev1.Secret{}
+		if err := s.Client.Get(nil, *s.Secret, current); err != nil {
+			return err
+		}
+		secret.ResourceVersion = current.ResourceVersion
+		return s.Client.Update(nil, secret)
+	})
+	return certs, err
+}
+
+func (s *secretCertWriter) dryrunWrite(secret *corev1.Secret) error {
+	if deadline, err := certRenewalDeadline(secret.Data[ServerCertName], s.RenewBefore, s.MinimumValidity); err == nil {
+		fmt.Fprintf(s.Writer, "# next renewal: %s\n", deadline.Format(time.RFC3339))
+	}
+	sec, err := yaml.Marshal(secret)
+	if err != nil {
+		return err
+	}
+	_, err = s.Writer.Write(sec)
+	return err
+}
+
+func (s *secretCertWriter) read() (*generator.Artifacts, error) {
+	if s.dryrun {
+		return nil, notFoundError{}
+	}
+	secret := &corev1.Secret{}
+	err := s.Client.Get(nil, *s.Secret, secret)
+	if apierrors.IsNotFound(err) {
+		return nil, notFoundError{err}
+	}
+	certs := secretToCerts(secret)
+	s.recordNextRenewal(certs)
+	return certs, err
+}
+
+// recordNextRenewal updates NextRenewal with the deadline computed from certs, if certs contains a
+// parseable certificate.
+func (s *secretCertWriter) recordNextRenewal(certs *generator.Artifacts) {
+	if certs == nil {
+		return
+	}
+	deadline, err := certRenewalDeadline(certs.Cert, s.RenewBefore, s.MinimumValidity)
+	if err != nil {
+		return
+	}
+	s.nextRenewalMu.Lock()
+	s.nextRenewal = deadline
+	s.nextRenewalMu.Unlock()
+}
+
+// valid reports whether certs is usable for dnsName and still outside of its renewal window.
+func (s *secretCertWriter) valid(certs *generator.Artifacts, dnsName string) bool {
+	if !validCert(certs, dnsName) {
+		return false
+	}
+	deadline, err := certRenewalDeadline(certs.Cert, s.RenewBefore, s.MinimumValidity)
+	if err != nil {
+		return false
+	}
+	return time.Now().Before(deadline)
+}
+
+func secretToCerts(secret *corev1.Secret) *generator.Artifacts {
+	if secret.Data == nil {
+		return nil
+	}
+	return &generator.Artifacts{
+		CACert: secret.Data[CACertName],
+		Cert:   secret.Data[ServerCertName],
+		Key:    secret.Data[ServerKeyName],
+	}
+}
+
+func certsToSecret(certs *generator.Artifacts, sec types.NamespacedName) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: sec.Namespace,
+			Name:      sec.Name,
+		},
+		Data: map[string][]byte{
+			CACertName:     certs.CACert,
+			ServerKeyName:  certs.Key,
+			ServerCertName: certs.Cert,
+		},
+	}
+}
+
+// Inject sets ownerReferences on the managed Secret pointing at objs, so it is garbage collected along
+// with the webhook configuration it serves. Without this, deleting and reinstalling a webhook leaves the
+// old Secret behind, and the old CA gets reused against a new server key until someone deletes it by hand.
+// objs must be MutatingWebhookConfiguration, ValidatingWebhookConfiguration, CustomResourceDefinition or
+// APIService objects. Like overwrite(), it retries on conflict instead of failing outright, so Inject
+// racing the leader's own renewal overwrite() on this same Secret doesn't just surface a stale-
+// resourceVersion error to the caller.
+func (s *secretCertWriter) Inject(objs ...runtime.Object) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret := &corev1.Secret{}
+		if err := s.Client.Get(nil, *s.Secret, secret); err != nil {
+			return err
+		}
+
+		for _, obj := range objs {
+			ref, err := s.ownerReference(obj)
+			if err != nil {
+				return err
+			}
+			secret.OwnerReferences = mergeOwnerReference(secret.OwnerReferences, ref)
+		}
+
+		return s.Client.Update(nil, secret)
+	})
+}
+
+// mergeOwnerReference adds ref to refs, replacing any existing entry with the same UID. This keeps
+// repeated calls to Inject (e.g. once per webhook configuration as each is registered) additive instead
+// of each call wiping out the owner references a previous call set.
+func mergeOwnerReference(refs []metav1.OwnerReference, ref metav1.OwnerReference) []metav1.OwnerReference {
+	for i := range refs {
+		if refs[i].UID == ref.UID {
+			refs[i] = ref
+			return refs
+		}
+	}
+	return append(refs, ref)
+}
+
+// ownerReference builds the OwnerReference for obj, re-fetching it through s.Client first if its UID
+// hasn't been populated yet (e.g. the caller only has the object it submitted, not what the API server
+// assigned it).
+func (s *secretCertWriter) ownerReference(obj runtime.Object) (metav1.OwnerReference, error) {
+	apiVersion, kind, err := ownedGVK(obj)
+	if err != nil {
+		return metav1.OwnerReference{}, err
+	}
+
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return metav1.OwnerReference{}, err
+	}
+
+	if accessor.GetUID() == "" {
+		key := types.NamespacedName{Name: accessor.GetName(), Namespace: accessor.GetNamespace()}
+		if err := s.Client.Get(nil, key, obj); err != nil {
+			return metav1.OwnerReference{}, fmt.Errorf("refreshing %s %s to resolve its UID: %v", kind, key, err)
+		}
+		if accessor, err = meta.Accessor(obj); err != nil {
+			return metav1.OwnerReference{}, err
+		}
+	}
+
+	blockOwnerDeletion := s.BlockOwnerDeletion
+	return metav1.OwnerReference{
+		APIVersion:         apiVersion,
+		Kind:               kind,
+		Name:               accessor.GetName(),
+		UID:                accessor.GetUID(),
+		BlockOwnerDeletion: &blockOwnerDeletion,
+	}, nil
+}
+
+// ownedGVK returns the apiVersion/kind Inject should record for obj, rejecting kinds it doesn't support.
+func ownedGVK(obj runtime.Object) (apiVersion, kind string, err error) {
+	switch obj.(type) {
+	case *admissionregistrationv1.MutatingWebhookConfiguration:
+		return "admissionregistration.k8s.io/v1", "MutatingWebhookConfiguration", nil
+	case *admissionregistrationv1.ValidatingWebhookConfiguration:
+		return "admissionregistration.k8s.io/v1", "ValidatingWebhookConfiguration", nil
+	case *apiextensionsv1.CustomResourceDefinition:
+		return "apiextensions.k8s.io/v1", "CustomResourceDefinition", nil
+	case *apiregistrationv1.APIService:
+		return "apiregistration.k8s.io/v1", "APIService", nil
+	default:
+		return "", "", fmt.Errorf("Inject does not support owning objects of type %T", obj)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package writer
+
+import (
+	"testing"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestOwnedGVK(t *testing.T) {
+	tests := map[string]struct {
+		obj         runtime.Object
+		wantVersion string
+		wantKind    string
+		wantErr     bool
+	}{
+		"mutating webhook configuration": {
+			obj:         &admissionregistrationv1.MutatingWebhookConfiguration{},
+			wantVersion: "admissionregistration.k8s.io/v1",
+			wantKind:    "MutatingWebhookConfiguration",
+		},
+		"validating webhook configuration": {
+			obj:         &admissionregistrationv1.ValidatingWebhookConfiguration{},
+			wantVersion: "admissionregistration.k8s.io/v1",
+			wantKind:    "ValidatingWebhookConfiguration",
+		},
+		"custom resource definition": {
+			obj:         &apiextensionsv1.CustomResourceDefinition{},
+			wantVersion: "apiextensions.k8s.io/v1",
+			wantKind:    "CustomResourceDefinition",
+		},
+		"unsupported kind": {
+			obj:     &corev1.Secret{},
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			apiVersion, kind, err := ownedGVK(tt.obj)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if apiVersion != tt.wantVersion || kind != tt.wantKind {
+				t.Fatalf("ownedGVK = (%q, %q), want (%q, %q)", apiVersion, kind, tt.wantVersion, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestMergeOwnerReference(t *testing.T) {
+	a := metav1.OwnerReference{UID: "a", Name: "first"}
+	b := metav1.OwnerReference{UID: "b", Name: "second"}
+	aUpdated := metav1.OwnerReference{UID: "a", Name: "first-renamed"}
+
+	refs := mergeOwnerReference(nil, a)
+	refs = mergeOwnerReference(refs, b)
+	if len(refs) != 2 {
+		t.Fatalf("expected 2 distinct owner references, got %d: %+v", len(refs), refs)
+	}
+
+	refs = mergeOwnerReference(refs, aUpdated)
+	if len(refs) != 2 {
+		t.Fatalf("expected a repeat UID to replace its entry instead of appending, got %d: %+v", len(refs), refs)
+	}
+	for _, ref := range refs {
+		if ref.UID == "a" && ref.Name != "first-renamed" {
+			t.Fatalf("expected the entry for UID a to be replaced, still have %q", ref.Name)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package writer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"sigs.k8s.io/controller-runtime/pkg/webhook/internal/cert/generator"
+)
+
+const (
+	// CAKeyName is the name of the CA private key in the secret/directory that holds the certs.
+	CAKeyName = "ca-key.pem"
+	// CACertName is the name of the CA certificate in the secret/directory that holds the certs.
+	CACertName = "ca-cert.pem"
+	// ServerKeyName is the name of the server private key in the secret/directory that holds the certs.
+	ServerKeyName = "key.pem"
+	// ServerCertName is the name of the server certificate in the secret/directory that holds the certs.
+	ServerCertName = "cert.pem"
+)
+
+// CertWriter provisions the certificate for the webhook server and, once it has one, can tie the
+// store that holds it to the objects it serves.
+type CertWriter interface {
+	// EnsureCert provisions certificates for a webhookClientConfig by writing the certificates to the k8s
+	// server or locally. It returns the certs, whether the certs changed, and an error (if any).
+	EnsureCert(dnsName string, dryrun bool) (*generator.Artifacts, bool, error)
+
+	// Inject ties the store holding the certs managed by this CertWriter to the given objects, e.g. by
+	// making them owners of the backing Secret. It does not write the CA bundle into the objects.
+	Inject(objs ...runtime.Object) error
+
+	// Watch returns a channel that receives a new Artifacts every time the underlying certificate
+	// changes, e.g. because an external PKI rotated it, so a long-lived webhook server can reload its
+	// TLS config without a restart. The channel is closed when ctx is done. Implementations that have
+	// no way to watch for changes return a non-nil error.
+	Watch(ctx context.Context) (<-chan *generator.Artifacts, error)
+}
+
+// certReadWriter reads and writes the certs to/from its backing store.
+type certReadWriter interface {
+	// read returns the certs currently held by the backing store.
+	read() (*generator.Artifacts, error)
+	// write generates brand new certs and persists them to the backing store.
+	write() (*generator.Artifacts, error)
+	// overwrite replaces the certs currently held by the backing store with brand new ones.
+	overwrite() (*generator.Artifacts, error)
+	// valid reports whether certs is still usable for dnsName, e.g. correctly named, unexpired, and
+	// outside of any implementation-specific renewal window.
+	valid(certs *generator.Artifacts, dnsName string) bool
+}
+
+// notFoundError is returned by read() when the backing store does not yet hold any certs.
+type notFoundError struct {
+	error
+}
+
+// alreadyExistError is returned by write() when the backing store already holds certs,
+// e.g. because another replica raced to create them first.
+type alreadyExistError struct {
+	error
+}
+
+// handleCommon implements the create-or-refresh logic shared by every CertWriter implementation:
+// write the certs if they don't exist yet, and refresh them if they are no longer valid for dnsName.
+func handleCommon(dnsName string, ch certReadWriter) (*generator.Artifacts, bool, error) {
+	if ch == nil {
+		return nil, false, errors.New("certReadWriter is nil")
+	}
+
+	certs, err := ch.read()
+	switch err.(type) {
+	case notFoundError:
+		certs, err = ch.write()
+		if _, ok := err.(alreadyExistError); ok {
+			// Someone else (e.g. another replica) beat us to it; read back what they wrote.
+			certs, err = ch.read()
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		return certs, true, nil
+	case nil:
+		// found, fall through to the validity check below.
+	default:
+		return nil, false, err
+	}
+
+	if !ch.valid(certs, dnsName) {
+		certs, err = ch.overwrite()
+		if err != nil {
+			return nil, false, err
+		}
+		return certs, true, nil
+	}
+	return certs, false, nil
+}
+
+// validCert returns true if certs contains a well-formed, non-expired certificate for dnsName.
+func validCert(certs *generator.Artifacts, dnsName string) bool {
+	if certs == nil || len(certs.Cert) == 0 || len(certs.Key) == 0 {
+		return false
+	}
+	pair, err := tls.X509KeyPair(certs.Cert, certs.Key)
+	if err != nil {
+		return false
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return false
+	}
+	if time.Now().After(leaf.NotAfter) {
+		return false
+	}
+	return leaf.VerifyHostname(dnsName) == nil
+}